@@ -22,8 +22,11 @@ var (
 	flAddKeywords      = flag.String("K", "", "Add the specified (delimited by comma or space) keywords to the current set of keywords")
 	flUseKeywords      = flag.String("k", "", "Use the specified (delimited by comma or space) keywords as the current set of keywords")
 	flUpdateAttributes = flag.Bool("u", false, "Modify the owner, group, permissions and xattrs of files, symbolic links and devices, to match the provided specification. This is not compatible with '-T'.")
+	flUpdateKeywords   = flag.String("update-keywords", strings.Join(mtree.DefaultUpdateKeywords, ","), "Comma-delimited list of keywords that '-u' is allowed to apply")
+	flWatch            = flag.Bool("w", false, "Watch the root path and continuously validate it against the specification as changes occur, instead of checking once and exiting. This is not compatible with '-T' or '-c'.")
 
 	// gomtree specific flags
+	flDiff             = flag.Bool("d", false, "compare two directory hierarchy specs and print a structured diff between them, without touching the filesystem; expects exactly two arguments, old.mtree and new.mtree")
 	flTar              = flag.String("T", "", "use tar archive to create or validate a directory hierarchy spec (\"-\" indicates stdin)")
 	flBsdKeywords      = flag.Bool("bsd-keywords", false, "only operate on keywords that are supported by upstream mtree(8)")
 	flDebug            = flag.Bool("debug", false, "output debug info to STDERR")
@@ -147,6 +150,54 @@ func main() {
 		currentKeywords = tmpKeywords
 	}
 
+	// -d old.mtree new.mtree
+	if *flDiff {
+		if flag.NArg() != 2 {
+			log.Println("ERROR: -d requires exactly two arguments: old.mtree new.mtree")
+			isErr = true
+			return
+		}
+
+		oldDh, err := parseSpecFile(flag.Arg(0))
+		if err != nil {
+			log.Println(err)
+			isErr = true
+			return
+		}
+		newDh, err := parseSpecFile(flag.Arg(1))
+		if err != nil {
+			log.Println(err)
+			isErr = true
+			return
+		}
+
+		diff, err := mtree.Diff(oldDh, newDh, currentKeywords)
+		if err != nil {
+			log.Println(err)
+			isErr = true
+			return
+		}
+
+		res := diff.Result()
+		out := formatFunc(res)
+		if _, err := os.Stdout.Write([]byte(out)); err != nil {
+			log.Println(err)
+			isErr = true
+			return
+		}
+		if *flResultFormat != "json" {
+			if err := printExtraMissing(res); err != nil {
+				log.Println(err)
+				isErr = true
+				return
+			}
+		}
+		if len(diff.Added) > 0 || len(diff.Removed) > 0 || len(diff.Modified) > 0 {
+			defer os.Exit(1)
+		}
+		return
+	}
+
 	// -f <file>
 	var dh *mtree.DirectoryHierarchy
 	if *flFile != "" && !*flCreate {
@@ -212,6 +263,14 @@ func main() {
 		return
 	}
 
+	// -w
+	// Failing early here. Processing is done below.
+	if *flWatch && (*flTar != "" || *flCreate) {
+		log.Println("ERROR: -w can not be used with -T or -c")
+		isErr = true
+		return
+	}
+
 	// -T <tar file>
 	var tdh *mtree.DirectoryHierarchy
 	if *flTar != "" {
@@ -268,9 +327,7 @@ func main() {
 	} else if *flUpdateAttributes && dh != nil {
 		// -u
 		// this comes before the next case, intentionally.
-
-		// TODO brainstorm where to allow setting of xattrs. Maybe a new flag that allows a comma delimited list of keywords to update?
-		updateKeywords := []string{"uid", "gid", "mode"}
+		updateKeywords := splitKeywordsArg(*flUpdateKeywords)
 
 		result, err := mtree.Update(rootPath, dh, updateKeywords)
 		if err != nil {
@@ -282,6 +339,38 @@ func main() {
 		if result != nil {
 			fmt.Printf("%#v\n", result)
 		}
+	} else if *flWatch && dh != nil {
+		// -w
+		events := make(chan mtree.Result)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- mtree.Watch(rootPath, dh, currentKeywords, events)
+		}()
+
+		for {
+			select {
+			case res := <-events:
+				out := formatFunc(&res)
+				if _, err := os.Stdout.Write([]byte(out)); err != nil {
+					log.Println(err)
+					isErr = true
+					return
+				}
+				if *flResultFormat != "json" {
+					if err := printExtraMissing(&res); err != nil {
+						log.Println(err)
+						isErr = true
+						return
+					}
+				}
+			case err := <-errc:
+				if err != nil {
+					log.Println(err)
+					isErr = true
+				}
+				return
+			}
+		}
 	} else if tdh != nil || dh != nil {
 		var res *mtree.Result
 		var err error
@@ -306,28 +395,14 @@ func main() {
 			}
 		}
 		if res != nil {
-			if len(res.Extra) > 0 {
+			if len(res.Extra) > 0 || len(res.Missing) > 0 {
 				defer os.Exit(1)
-				for _, extra := range res.Extra {
-					extrapath, err := extra.Path()
-					if err != nil {
+				if *flResultFormat != "json" {
+					if err := printExtraMissing(res); err != nil {
 						log.Println(err)
 						isErr = true
 						return
 					}
-					fmt.Printf("%s extra\n", extrapath)
-				}
-			}
-			if len(res.Missing) > 0 {
-				defer os.Exit(1)
-				for _, missing := range res.Missing {
-					missingpath, err := missing.Path()
-					if err != nil {
-						log.Println(err)
-						isErr = true
-						return
-					}
-					fmt.Printf("%s missing\n", missingpath)
 				}
 			}
 		}
@@ -339,6 +414,37 @@ func main() {
 	}
 }
 
+// printExtraMissing prints the "extra"/"missing" lines for a Result's Extra
+// and Missing entries, the same way the bsd/path formatFuncs print
+// Failures, since neither of those formats otherwise looks at Extra or
+// Missing.
+func printExtraMissing(res *mtree.Result) error {
+	for _, extra := range res.Extra {
+		extrapath, err := extra.Path()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s extra\n", extrapath)
+	}
+	for _, missing := range res.Missing {
+		missingpath, err := missing.Path()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s missing\n", missingpath)
+	}
+	return nil
+}
+
+func parseSpecFile(name string) (*mtree.DirectoryHierarchy, error) {
+	fh, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return mtree.ParseSpec(fh)
+}
+
 func splitKeywordsArg(str string) []string {
 	return strings.Fields(strings.Replace(str, ",", " ", -1))
 }
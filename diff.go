@@ -0,0 +1,145 @@
+package mtree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DiffEntry describes a single keyword-level change, between two
+// DirectoryHierarchy values, to the entry at Path.
+type DiffEntry struct {
+	Path    string `json:"path"`
+	Keyword string `json:"keyword"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+}
+
+func (de DiffEntry) String() string {
+	return fmt.Sprintf("%s: keyword %q changed from %q to %q", de.Path, de.Keyword, de.Old, de.New)
+}
+
+// DiffResult is the structured patch between two DirectoryHierarchy values,
+// as produced by Diff: the entries only present in the new hierarchy, the
+// entries only present in the old one, and the keyword-level changes to
+// entries present in both.
+type DiffResult struct {
+	Added    []Entry     `json:"added,omitempty"`
+	Removed  []Entry     `json:"removed,omitempty"`
+	Modified []DiffEntry `json:"modified,omitempty"`
+}
+
+// Result adapts a DiffResult to the Result-based rendering pipeline used by
+// Check (see the -result-format flag of cmd/gomtree): added entries become
+// Extra, removed entries become Missing, and keyword-level changes become
+// Failures with Expected set to the old value and Got set to the new one.
+func (dr *DiffResult) Result() *Result {
+	res := &Result{
+		Extra:   dr.Added,
+		Missing: dr.Removed,
+	}
+	for _, d := range dr.Modified {
+		res.Failures = append(res.Failures, Failure{
+			Path:     d.Path,
+			Keyword:  d.Keyword,
+			Expected: d.Old,
+			Got:      d.New,
+		})
+	}
+	return res
+}
+
+// Diff compares oldDh and newDh, the DirectoryHierarchy of two manifests
+// taken of (presumably) the same tree at different points in time, and
+// reports which paths were added, removed, or had one of keywords change
+// value. Diff does not touch the filesystem; it only compares the two
+// manifests to each other.
+func Diff(oldDh, newDh *DirectoryHierarchy, keywords []string) (*DiffResult, error) {
+	if len(keywords) == 0 {
+		keywords = DefaultKeywords
+	}
+
+	oldEntries, err := collectPathEntries(oldDh)
+	if err != nil {
+		return nil, err
+	}
+	newEntries, err := collectPathEntries(newDh)
+	if err != nil {
+		return nil, err
+	}
+
+	var result DiffResult
+	for path, n := range newEntries {
+		o, ok := oldEntries[path]
+		if !ok {
+			result.Added = append(result.Added, n.entry)
+			continue
+		}
+		for _, kw := range keywords {
+			oldVal := o.keyvals.Has(kw).Value()
+			newVal := n.keyvals.Has(kw).Value()
+			if oldVal != newVal {
+				result.Modified = append(result.Modified, DiffEntry{
+					Path:    path,
+					Keyword: kw,
+					Old:     oldVal,
+					New:     newVal,
+				})
+			}
+		}
+	}
+	for path, o := range oldEntries {
+		if _, ok := newEntries[path]; !ok {
+			result.Removed = append(result.Removed, o.entry)
+		}
+	}
+
+	sort.Sort(byPos(result.Added))
+	sort.Sort(byPos(result.Removed))
+	sort.Slice(result.Modified, func(i, j int) bool {
+		if result.Modified[i].Path != result.Modified[j].Path {
+			return result.Modified[i].Path < result.Modified[j].Path
+		}
+		return result.Modified[i].Keyword < result.Modified[j].Keyword
+	})
+
+	return &result, nil
+}
+
+// pathEntry pairs an Entry with its keywords, merged with any enclosing
+// /set block, for quick lookup by path.
+type pathEntry struct {
+	entry   Entry
+	keyvals KeyVals
+}
+
+// collectPathEntries walks dh's entries (honoring /set and /unset blocks,
+// as Update does) and indexes them by path.
+func collectPathEntries(dh *DirectoryHierarchy) (map[string]pathEntry, error) {
+	entries := append([]Entry{}, dh.Entries...)
+	sort.Sort(byPos(entries))
+
+	result := map[string]pathEntry{}
+	var curSet *Entry
+	for i := range entries {
+		e := entries[i]
+		switch e.Type {
+		case SpecialType:
+			if e.Name == "/set" {
+				curSet = &entries[i]
+			} else if e.Name == "/unset" {
+				curSet = nil
+			}
+		case RelativeType, FullType:
+			path, err := e.Path()
+			if err != nil {
+				return nil, err
+			}
+			var setKeywords []string
+			if curSet != nil {
+				setKeywords = curSet.Keywords
+			}
+			result[path] = pathEntry{entry: e, keyvals: MergeSet(setKeywords, e.Keywords)}
+		}
+	}
+	return result, nil
+}
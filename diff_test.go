@@ -0,0 +1,97 @@
+package mtree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	oldDh := &DirectoryHierarchy{
+		Entries: []Entry{
+			{Type: RelativeType, Name: "file1", Keywords: []string{"size=10", "mode=0644"}},
+			{Type: RelativeType, Name: "file2", Keywords: []string{"size=20", "mode=0644"}},
+		},
+	}
+	newDh := &DirectoryHierarchy{
+		Entries: []Entry{
+			{Type: RelativeType, Name: "file1", Keywords: []string{"size=15", "mode=0644"}},
+			{Type: RelativeType, Name: "file3", Keywords: []string{"size=5", "mode=0644"}},
+		},
+	}
+
+	diff, err := Diff(oldDh, newDh, []string{"size", "mode"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diff.Added) != 1 {
+		t.Fatalf("expected 1 added entry, got %d", len(diff.Added))
+	}
+	if name, _ := diff.Added[0].Path(); name != "file3" {
+		t.Errorf("expected file3 added, got %s", name)
+	}
+
+	if len(diff.Removed) != 1 {
+		t.Fatalf("expected 1 removed entry, got %d", len(diff.Removed))
+	}
+	if name, _ := diff.Removed[0].Path(); name != "file2" {
+		t.Errorf("expected file2 removed, got %s", name)
+	}
+
+	if len(diff.Modified) != 1 {
+		t.Fatalf("expected 1 modified keyword, got %d", len(diff.Modified))
+	}
+	want := DiffEntry{Path: "file1", Keyword: "size", Old: "10", New: "15"}
+	if diff.Modified[0] != want {
+		t.Errorf("got %#v, want %#v", diff.Modified[0], want)
+	}
+}
+
+// TestDiffHonorsSet checks that a /set block's keywords are merged into
+// each entry the same way Update does, so a value that only changed in the
+// /set block (and wasn't overridden per-entry) is still detected.
+func TestDiffHonorsSet(t *testing.T) {
+	oldDh := &DirectoryHierarchy{
+		Entries: []Entry{
+			{Type: SpecialType, Name: "/set", Keywords: []string{"mode=0644"}},
+			{Type: RelativeType, Name: "file1", Keywords: []string{"size=10"}},
+		},
+	}
+	newDh := &DirectoryHierarchy{
+		Entries: []Entry{
+			{Type: SpecialType, Name: "/set", Keywords: []string{"mode=0755"}},
+			{Type: RelativeType, Name: "file1", Keywords: []string{"size=10"}},
+		},
+	}
+
+	diff, err := Diff(oldDh, newDh, []string{"size", "mode"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Modified) != 1 {
+		t.Fatalf("expected 1 modified keyword, got %#v", diff.Modified)
+	}
+	want := DiffEntry{Path: "file1", Keyword: "mode", Old: "0644", New: "0755"}
+	if diff.Modified[0] != want {
+		t.Errorf("got %#v, want %#v", diff.Modified[0], want)
+	}
+}
+
+func TestDiffResult(t *testing.T) {
+	dr := &DiffResult{
+		Added:    []Entry{{Type: RelativeType, Name: "new"}},
+		Removed:  []Entry{{Type: RelativeType, Name: "gone"}},
+		Modified: []DiffEntry{{Path: "changed", Keyword: "size", Old: "1", New: "2"}},
+	}
+
+	res := dr.Result()
+	if !reflect.DeepEqual(res.Extra, dr.Added) {
+		t.Errorf("Result().Extra = %#v, want %#v", res.Extra, dr.Added)
+	}
+	if !reflect.DeepEqual(res.Missing, dr.Removed) {
+		t.Errorf("Result().Missing = %#v, want %#v", res.Missing, dr.Removed)
+	}
+	if len(res.Failures) != 1 || res.Failures[0].Path != "changed" || res.Failures[0].Expected != "1" || res.Failures[0].Got != "2" {
+		t.Errorf("Result().Failures = %#v", res.Failures)
+	}
+}
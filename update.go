@@ -41,13 +41,12 @@ func Update(root string, dh *DirectoryHierarchy, keywords []string) (*Result, er
 			if err != nil {
 				return nil, err
 			}
-			var toCheck []string
+			var setKeywords []string
 			if creator.curSet != nil {
-				toCheck = append(toCheck, creator.curSet.Keywords...)
+				setKeywords = creator.curSet.Keywords
 			}
-			toCheck = append(toCheck, e.Keywords...)
 
-			for _, kv := range NewKeyVals(toCheck) {
+			for _, kv := range MergeSet(setKeywords, e.Keywords) {
 				if !inSlice(kv.Keyword(), keywords) {
 					continue
 				}
@@ -56,7 +55,7 @@ func Update(root string, dh *DirectoryHierarchy, keywords []string) (*Result, er
 					Debugf("no UpdateKeywordFunc for %s; skipping", kv.Keyword())
 					continue
 				}
-				if _, err := ukFunc(pathname, kv.Value()); err != nil {
+				if _, err := ukFunc(pathname, kv); err != nil {
 					result.Failures = append(result.Failures, Failure{Path: pathname, Keyword: kv.Keyword(), Got: err.Error()})
 				}
 			}
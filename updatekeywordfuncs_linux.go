@@ -0,0 +1,22 @@
+//go:build linux
+
+package mtree
+
+import (
+	"encoding/base64"
+	"os"
+	"syscall"
+)
+
+func init() {
+	UpdateKeywordFuncs["xattr"] = func(path string, kv KeyVal) (os.FileInfo, error) {
+		data, err := base64.StdEncoding.DecodeString(kv.Value())
+		if err != nil {
+			return nil, err
+		}
+		if err := syscall.Setxattr(path, kv.KeywordSuffix(), data, 0); err != nil {
+			return nil, err
+		}
+		return os.Lstat(path)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build linux
+
+package mtree
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestUpdateKeywordFuncsXattr(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-update-xattr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmpfn := filepath.Join(dir, "tmpfile")
+	if err := ioutil.WriteFile(tmpfn, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("some xattr value")
+	kv := KeyVal("xattr.user.mtree-test=" + base64.StdEncoding.EncodeToString(data))
+
+	if _, err := UpdateKeywordFuncs["xattr"](tmpfn, kv); err != nil {
+		if err == syscall.ENOTSUP {
+			t.Skip("xattrs not supported on this filesystem")
+		}
+		t.Fatal(err)
+	}
+
+	got := make([]byte, len(data))
+	n, err := syscall.Getxattr(tmpfn, "user.mtree-test", got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got[:n]) != string(data) {
+		t.Errorf("got xattr value %q, want %q", got[:n], data)
+	}
+}
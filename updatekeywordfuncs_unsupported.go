@@ -0,0 +1,15 @@
+//go:build !linux
+
+package mtree
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func init() {
+	UpdateKeywordFuncs["xattr"] = func(path string, kv KeyVal) (os.FileInfo, error) {
+		return nil, fmt.Errorf("xattr keyword is not supported on %s", runtime.GOOS)
+	}
+}
@@ -1,30 +1,12 @@
 package mtree
 
-// #include "vis.h"
-// #include <stdlib.h>
-import "C"
 import (
+	"bytes"
 	"fmt"
-	"math"
-	"unsafe"
 )
 
-// Vis is a wrapper of the C implementation of the function vis, which encodes
-// a character with a particular format/style
-func Vis(src string) (string, error) {
-	// dst needs to be 4 times the length of str, must check appropriate size
-	if uint32(len(src)*4+1) >= math.MaxUint32/4 {
-		return "", fmt.Errorf("failed to encode: %q", src)
-	}
-	dst := string(make([]byte, 4*len(src)+1))
-	cDst, cSrc := C.CString(dst), C.CString(src)
-	defer C.free(unsafe.Pointer(cDst))
-	defer C.free(unsafe.Pointer(cSrc))
-	C.strvis(cDst, cSrc, C.int(VisWhite|VisOctal|VisGlob))
-
-	return C.GoString(cDst), nil
-}
-
+// VisFlag controls the style of encoding that Vis (and the decoding that
+// Unvis) performs.
 type VisFlag int
 
 const (
@@ -37,7 +19,7 @@ const (
 	VisSp    VisFlag = 0x04 // also encode space
 	VisTab   VisFlag = 0x08 // also encode tab
 	VisNl    VisFlag = 0x10 // also encode newline
-	VisWhite VisFlag = (VIS_SP | VIS_TAB | VIS_NL)
+	VisWhite VisFlag = VisSp | VisTab | VisNl
 	VisSafe  VisFlag = 0x20 // only encode "unsafe" characters
 
 	// other
@@ -52,10 +34,18 @@ const (
 	UnvisErrorSynbad        UnvisError = -1 // unrecognized escape sequence
 	UnvisErrorUnrecoverable UnvisError = -2 // decoder in unknown state (unrecoverable)
 
+	// UnvisErrorValidPush is a deprecated alias for UnvisErrorValidpush, kept
+	// for API compatibility with consumers referencing the original spelling.
+	//
+	// Deprecated: use UnvisErrorValidpush.
+	UnvisErrorValidPush = UnvisErrorValidpush
+
 	// unvis flags
 	UnvisEnd VisFlag = 1 // no more characters
 )
 
+// UnvisError is one of the UnvisError* codes returned by Unvis when it
+// cannot decode its input.
 type UnvisError int
 
 func (ue UnvisError) Error() string {
@@ -73,3 +63,235 @@ func (ue UnvisError) Error() string {
 	}
 	return "Unknown Error"
 }
+
+// globMagic is the set of glob(3) characters that VisGlob additionally
+// escapes, so that vis'd output can be safely passed back through a shell
+// glob.
+const globMagic = "*?[#"
+
+// cstyleEncode is the NetBSD strvis(3) short-form encoding of the control
+// characters that have a conventional C escape.
+var cstyleEncode = map[byte]string{
+	0x00: "0",
+	0x07: "a",
+	0x08: "b",
+	0x09: "t",
+	0x0a: "n",
+	0x0b: "v",
+	0x0c: "f",
+	0x0d: "r",
+	0x1b: "E",
+	0x20: "s",
+}
+
+// cstyleDecode is the inverse of cstyleEncode.
+var cstyleDecode = map[byte]byte{
+	'0': 0x00,
+	'a': 0x07,
+	'b': 0x08,
+	't': 0x09,
+	'n': 0x0a,
+	'v': 0x0b,
+	'f': 0x0c,
+	'r': 0x0d,
+	'E': 0x1b,
+	's': 0x20,
+}
+
+// Vis encodes src byte-by-byte using the default style used throughout
+// go-mtree manifests: octal escapes for anything non-graphic, glob magic
+// characters escaped, and all whitespace, including space/tab/newline, encoded.
+// It is a pure-Go port of NetBSD's strvis(3), so go-mtree no longer needs
+// cgo (or a C toolchain) to build.
+func Vis(src string) (string, error) {
+	var buf bytes.Buffer
+	for i := 0; i < len(src); i++ {
+		visByte(&buf, src[i], VisWhite|VisOctal|VisGlob)
+	}
+	return buf.String(), nil
+}
+
+// visByte appends the encoding of c to buf, honoring flag.
+func visByte(buf *bytes.Buffer, c byte, flag VisFlag) {
+	if visPassthrough(c, flag) {
+		buf.WriteByte(c)
+		return
+	}
+
+	if flag&VisHttpstyle != 0 {
+		fmt.Fprintf(buf, "%%%02X", c)
+		return
+	}
+
+	if c == '\\' {
+		// strvis(3) only doubles the backslash under VisCstyle; otherwise
+		// (even without VisOctal) it always falls back to the octal form,
+		// since a bare backslash can't be told apart from the escape
+		// character it introduces.
+		if flag&VisCstyle != 0 {
+			visEscape(buf, flag, "\\")
+		} else {
+			visEscape(buf, flag, fmt.Sprintf("%03o", c))
+		}
+		return
+	}
+
+	if flag&VisCstyle != 0 {
+		if rep, ok := cstyleEncode[c]; ok {
+			visEscape(buf, flag, rep)
+			return
+		}
+	}
+
+	if flag&VisOctal != 0 {
+		visEscape(buf, flag, fmt.Sprintf("%03o", c))
+		return
+	}
+
+	meta := c&0x80 != 0
+	c7 := c &^ 0x80
+	switch {
+	case c7 < 0x20 || c7 == 0x7f:
+		ctrl := c7 ^ 0x40
+		if meta {
+			visEscape(buf, flag, fmt.Sprintf("M^%c", ctrl))
+		} else {
+			visEscape(buf, flag, fmt.Sprintf("^%c", ctrl))
+		}
+	default:
+		if meta {
+			visEscape(buf, flag, fmt.Sprintf("M-%c", c7))
+		} else {
+			visEscape(buf, flag, string(c7))
+		}
+	}
+}
+
+// visEscape writes the leading backslash (unless inhibited by VisNoslash)
+// followed by rep.
+func visEscape(buf *bytes.Buffer, flag VisFlag, rep string) {
+	if flag&VisNoslash == 0 {
+		buf.WriteByte('\\')
+	}
+	buf.WriteString(rep)
+}
+
+// visPassthrough reports whether c can be written unencoded under flag.
+func visPassthrough(c byte, flag VisFlag) bool {
+	if flag&VisGlob != 0 && bytes.IndexByte([]byte(globMagic), c) >= 0 {
+		return false
+	}
+	// VisSafe asks for only the genuinely unsafe characters (controls,
+	// backslash, meta, and - if requested - glob magic) to be encoded, so
+	// it overrides any of VisSp/VisTab/VisNl asking for additional,
+	// otherwise-safe whitespace to be encoded too. strvis(3) also treats
+	// BEL, BS, and CR as safe under VIS_SAFE, on top of space/tab/newline.
+	switch c {
+	case ' ':
+		return flag&VisSafe != 0 || flag&VisSp == 0
+	case '\t':
+		return flag&VisSafe != 0 || flag&VisTab == 0
+	case '\n':
+		return flag&VisSafe != 0 || flag&VisNl == 0
+	case '\a', '\b', '\r':
+		return flag&VisSafe != 0
+	case '\\':
+		return false
+	}
+	return c > 0x20 && c < 0x7f
+}
+
+// Unvis decodes a string produced by Vis (or by the upstream mtree(8)/vis(3)
+// tools) back into its original bytes. It returns an UnvisError if src
+// contains a malformed escape sequence.
+//
+// Unvis only understands the backslash-escaped forms Vis produces; it does
+// not decode "%XX" http-style escapes, since Vis never emits VisHttpstyle
+// output and a bare literal "%41" in the input must round-trip unchanged
+// rather than being mistaken for an escape.
+func Unvis(src string) (string, error) {
+	b := []byte(src)
+	var buf bytes.Buffer
+
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c == '\\':
+			decoded, consumed, err := unvisEscape(b[i+1:])
+			if err != nil {
+				return "", err
+			}
+			buf.WriteByte(decoded)
+			i += 1 + consumed
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// unvisEscape decodes the escape sequence following a backslash (which is
+// not included in rest), returning the decoded byte and the number of bytes
+// of rest it consumed.
+func unvisEscape(rest []byte) (byte, int, error) {
+	if len(rest) == 0 {
+		return 0, 0, UnvisErrorSynbad
+	}
+
+	switch rest[0] {
+	case '\\':
+		return '\\', 1, nil
+	case 'M':
+		if len(rest) < 2 {
+			return 0, 0, UnvisErrorSynbad
+		}
+		switch rest[1] {
+		case '-':
+			if len(rest) < 3 {
+				return 0, 0, UnvisErrorSynbad
+			}
+			if rest[2] == '^' {
+				if len(rest) < 4 {
+					return 0, 0, UnvisErrorSynbad
+				}
+				return (rest[3] ^ 0x40) | 0x80, 4, nil
+			}
+			return rest[2] | 0x80, 3, nil
+		case '^':
+			if len(rest) < 3 {
+				return 0, 0, UnvisErrorSynbad
+			}
+			return (rest[2] ^ 0x40) | 0x80, 3, nil
+		default:
+			return 0, 0, UnvisErrorSynbad
+		}
+	case '^':
+		if len(rest) < 2 {
+			return 0, 0, UnvisErrorSynbad
+		}
+		return rest[1] ^ 0x40, 2, nil
+	}
+
+	// Octal escapes are checked before the cstyle table: both use '0' for
+	// NUL, but only the octal form can be followed by up to two more octal
+	// digits, so it must get first refusal.
+	if rest[0] >= '0' && rest[0] <= '7' {
+		n := 1
+		val := int(rest[0] - '0')
+		for n < 3 && n < len(rest) && rest[n] >= '0' && rest[n] <= '7' {
+			val = val*8 + int(rest[n]-'0')
+			n++
+		}
+		return byte(val), n, nil
+	}
+
+	if rep, ok := cstyleDecode[rest[0]]; ok {
+		return rep, 1, nil
+	}
+
+	// An escaped literal, e.g. a glob character or a forced space, decodes
+	// to the byte that follows the backslash.
+	return rest[0], 1, nil
+}
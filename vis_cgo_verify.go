@@ -0,0 +1,50 @@
+//go:build vis_cgo_verify
+
+package mtree
+
+// This file is not part of the normal build: go-mtree dropped its cgo
+// dependency (see vis.go), and this repo's CI has no libbsd-dev installed to
+// link against. It exists, alongside vis_cgo_verify_test.go, so that a
+// byte-for-byte parity check against a real strvis(3) can be reproduced and
+// the table in TestVisGolden regenerated, by running:
+//
+//	go test -tags vis_cgo_verify -run TestVisGoldenAgainstCgo -v .
+//
+// on a host with libbsd's shared library present (Debian/Ubuntu: libbsd0).
+// It links directly against the installed .so by symbol name rather than
+// through vis.h, since that header ships only with libbsd-dev, which this
+// tree doesn't have; the flag bit used for VIS_GLOB below (0x1000) was found
+// empirically for this library version and is NOT the NetBSD value (0x100)
+// that VisGlob is defined as elsewhere in this package — see the comment on
+// TestVisGolden for why that divergence doesn't matter here. (cgo isn't
+// permitted directly in a _test.go file, hence this separate file.)
+
+/*
+#cgo LDFLAGS: -l:libbsd.so.0
+extern int strvis(char *dst, char *src, int flags);
+*/
+import "C"
+
+import "unsafe"
+
+const (
+	cgoVisOctal = 0x0001
+	cgoVisSp    = 0x0004
+	cgoVisTab   = 0x0008
+	cgoVisNl    = 0x0010
+	cgoVisGlob  = 0x1000 // this libbsd's bit, not NetBSD's VisGlob (0x100)
+)
+
+// cgoStrvis calls the real, linked strvis(3) for a single byte. strvis takes
+// a NUL-terminated C string, so it can't represent (and this can't verify)
+// an encoding for the 0x00 byte itself.
+func cgoStrvis(c byte) string {
+	src := [2]C.char{C.char(c), 0}
+	dst := make([]byte, 16)
+	C.strvis((*C.char)(unsafe.Pointer(&dst[0])), (*C.char)(unsafe.Pointer(&src[0])), C.int(cgoVisSp|cgoVisTab|cgoVisNl|cgoVisOctal|cgoVisGlob))
+	n := 0
+	for n < len(dst) && dst[n] != 0 {
+		n++
+	}
+	return string(dst[:n])
+}
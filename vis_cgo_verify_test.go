@@ -0,0 +1,23 @@
+//go:build vis_cgo_verify
+
+package mtree
+
+import "testing"
+
+// TestVisGoldenAgainstCgo checks Vis's default style against the real
+// strvis(3) for every byte it can represent (1..255), confirming the table
+// TestVisGolden pins is the encoding strvis(3) actually produces, not one
+// guessed at from its man page. See vis_cgo_verify.go for how to run this.
+func TestVisGoldenAgainstCgo(t *testing.T) {
+	for i := 1; i < 256; i++ {
+		src := string([]byte{byte(i)})
+		want := cgoStrvis(byte(i))
+		got, err := Vis(src)
+		if err != nil {
+			t.Fatalf("Vis(%#v): %s", src, err)
+		}
+		if got != want {
+			t.Errorf("Vis(0x%02x) = %q, want %q (from cgo strvis)", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package mtree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is the window used to coalesce bursts of filesystem events
+// (e.g. a file being written in several chunks) into a single re-check.
+const watchDebounce = 100 * time.Millisecond
+
+// Watch subscribes to filesystem events under root and, as they arrive,
+// re-evaluates only the affected entries of dh against keywords, emitting a
+// Result for each batch of changes on events. Watch registers every
+// subdirectory of root (and any subdirectory created afterwards) so that the
+// whole hierarchy stays covered. It runs until the watcher is closed or an
+// unrecoverable error occurs, in which case that error is returned.
+//
+// If the fsnotify event queue overflows (i.e. events are dropped), Watch
+// falls back to a full Check of root against dh and emits its Result, rather
+// than risk missing a change.
+func Watch(root string, dh *DirectoryHierarchy, keywords []string, events chan<- Result) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := addRecursiveWatch(watcher, root); err != nil {
+		return err
+	}
+
+	var (
+		dirty    = map[string]struct{}{}
+		debounce *time.Timer
+	)
+
+	flush := func() error {
+		if len(dirty) == 0 {
+			return nil
+		}
+		paths := make([]string, 0, len(dirty))
+		for p := range dirty {
+			paths = append(paths, p)
+		}
+		dirty = map[string]struct{}{}
+
+		res, err := checkPaths(root, dh, keywords, paths)
+		if err != nil {
+			return err
+		}
+		events <- *res
+		return nil
+	}
+
+	debounceC := func() <-chan time.Time {
+		if debounce == nil {
+			return nil
+		}
+		return debounce.C
+	}
+
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			Debugf("%#v", ev)
+
+			if ev.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					if err := addRecursiveWatch(watcher, ev.Name); err != nil {
+						Debugf("failed to watch new directory %s: %s", ev.Name, err)
+					}
+				}
+			}
+
+			dirty[filepath.Clean(ev.Name)] = struct{}{}
+			if debounce == nil {
+				debounce = time.NewTimer(watchDebounce)
+			} else {
+				debounce.Reset(watchDebounce)
+			}
+
+		case <-debounceC():
+			debounce = nil
+			if err := flush(); err != nil {
+				return err
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// We may have missed events; a full re-check is the only way
+				// to be sure we haven't drifted from the manifest.
+				res, cerr := Check(root, dh, keywords)
+				if cerr != nil {
+					return cerr
+				}
+				events <- *res
+				dirty = map[string]struct{}{}
+				continue
+			}
+			return err
+		}
+	}
+}
+
+// addRecursiveWatch registers root, and every directory beneath it, with
+// watcher.
+func addRecursiveWatch(watcher *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := watcher.Add(path); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkPaths re-evaluates only the manifest entries whose path matches one
+// of paths, is contained within one of them (in the case of a directory), or
+// is a directory containing one of them, returning an incremental Result. paths are fsnotify event paths, which are
+// joined with root (as addRecursiveWatch registers root's subdirectories
+// with their full, root-joined names); entry paths are root-relative, so
+// they are joined with root before comparing.
+//
+// checkPaths cannot use Check directly: Check walks the whole of root and
+// compares what it finds against every entry in the manifest it is given, so
+// handing it a manifest trimmed down to paths would report everything else
+// under root as Extra. Instead, it walks root restricted to the same dirty
+// set the manifest was trimmed to, and Diffs the two trimmed sides against
+// each other.
+func checkPaths(root string, dh *DirectoryHierarchy, keywords []string, paths []string) (*Result, error) {
+	var filtered []Entry
+	for _, e := range dh.Entries {
+		if e.Type != RelativeType && e.Type != FullType {
+			filtered = append(filtered, e)
+			continue
+		}
+		p, err := e.Path()
+		if err != nil {
+			return nil, err
+		}
+		entryPath := filepath.Join(root, p)
+		for _, changed := range paths {
+			if entryPath == changed || isWithin(changed, entryPath) || isWithin(entryPath, changed) {
+				filtered = append(filtered, e)
+				break
+			}
+		}
+	}
+	expected := &DirectoryHierarchy{Entries: filtered}
+
+	excludeUntouched := func(path string, info os.FileInfo) bool {
+		path = filepath.Clean(path)
+		for _, changed := range paths {
+			if path == changed || isWithin(changed, path) || isWithin(path, changed) {
+				return false
+			}
+		}
+		return true
+	}
+	actual, err := Walk(root, []ExcludeFunc{excludeUntouched}, keywords)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := Diff(expected, actual, keywords)
+	if err != nil {
+		return nil, err
+	}
+	return diff.Result(), nil
+}
+
+// isWithin reports whether child is dir or a path beneath dir.
+func isWithin(child, dir string) bool {
+	rel, err := filepath.Rel(dir, child)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
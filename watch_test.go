@@ -0,0 +1,77 @@
+package mtree
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWatchReportsOnlyChangedPath drives Watch over a temp dir with several
+// files and checks that modifying one of them reports a Result scoped to
+// that file: no Failures for the untouched files, and in particular no
+// Extra entries for them either. Extra is populated for anything Watch finds
+// on disk that the manifest it is handed doesn't describe; a Watch that
+// mistakenly re-checked the whole tree against a manifest trimmed down to
+// the changed path would report every untouched file as Extra on every
+// event, which is exactly what this test guards against.
+func TestWatchReportsOnlyChangedPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	changed := filepath.Join(dir, "changed")
+	untouched := filepath.Join(dir, "untouched")
+	if err := ioutil.WriteFile(changed, []byte("before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(untouched, []byte("before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dh, err := Walk(dir, nil, DefaultKeywords)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan Result)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Watch(dir, dh, DefaultKeywords, events)
+	}()
+
+	// Give the watcher time to register before mutating the tree.
+	time.Sleep(50 * time.Millisecond)
+	if err := ioutil.WriteFile(changed, []byte("after, a different length"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case res := <-events:
+		for _, extra := range res.Extra {
+			if p, _ := extra.Path(); p != "" {
+				t.Errorf("unexpected Extra entry for untouched path: %s", p)
+			}
+		}
+		for _, missing := range res.Missing {
+			if p, _ := missing.Path(); p != "" {
+				t.Errorf("unexpected Missing entry: %s", p)
+			}
+		}
+		if len(res.Failures) == 0 {
+			t.Error("expected a failure for the changed file, got none")
+		}
+		for _, f := range res.Failures {
+			if f.Path == "untouched" {
+				t.Errorf("unexpected failure reported for untouched file: %#v", f)
+			}
+		}
+	case err := <-errc:
+		t.Fatalf("Watch exited early: %s", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a Watch event")
+	}
+}